@@ -32,7 +32,7 @@ type Forsp struct {
 	done bool
 }
 
-func (f *Forsp) primBye(_ **forsp.Obj) { f.done = true }
+func (f *Forsp) primBye(_ **forsp.Scope) error { f.done = true; return nil }
 
 func New() *Forsp {
 	f := Forsp{
@@ -44,6 +44,24 @@ func New() *Forsp {
 	return &f
 }
 
+// readEval reads and evaluates a single top-level form, recovering
+// from any internal invariant panic so the REPL can report it and
+// keep going rather than crashing the process.
+func (f *Forsp) readEval() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	obj, err := f.Read()
+	if err != nil {
+		return err
+	}
+
+	return f.Compute(obj)
+}
+
 func main() {
 	f := New()
 
@@ -54,13 +72,15 @@ func main() {
 			os.Exit(1)
 		}
 
-		if err := f.SetReader(r); err != nil {
+		if err := f.SetReader(r, os.Args[1]); err != nil {
 			fmt.Fprintln(os.Stderr, err)
 			os.Exit(1)
 		}
 
-		obj := f.Read()
-		f.Compute(obj)
+		if err := f.readEval(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
 
 		return
 	}
@@ -73,12 +93,13 @@ func main() {
 			os.Exit(1)
 		}
 
-		if err := f.SetReader(strings.NewReader(string(line))); err != nil {
+		if err := f.SetReader(strings.NewReader(string(line)), "<stdin>"); err != nil {
 			fmt.Fprintln(os.Stderr, err)
 			os.Exit(1)
 		}
 
-		obj := f.Read()
-		f.Compute(obj)
+		if err := f.readEval(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
 	}
 }
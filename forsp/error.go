@@ -0,0 +1,18 @@
+package forsp
+
+// ForspError is the error type returned by Read, Compute, ComputeEnv
+// and eval when something goes wrong, in place of the panics they used
+// to raise. Pos is the position of the input being read or evaluated
+// at the time of failure, when one is available.
+type ForspError struct {
+	Pos Position
+	Msg string
+}
+
+func (e *ForspError) Error() string {
+	if e.Pos.IsValid() || e.Pos.Filename != "" {
+		return e.Pos.String() + ": " + e.Msg
+	}
+
+	return e.Msg
+}
@@ -3,6 +3,7 @@ package forsp
 import (
 	"fmt"
 	"io"
+	"os"
 	"strconv"
 )
 
@@ -25,9 +26,10 @@ type (
 		car, cdr *Obj
 	}
 	Closure struct {
-		body, env *Obj
+		body *Obj
+		env  *Scope
 	}
-	Primitive func(env **Obj)
+	Primitive func(env **Scope) error
 )
 
 type Obj struct {
@@ -71,11 +73,15 @@ type Forsp struct {
 	AtomPop       *Obj // atom: pop
 
 	// Stack/env
-	Stack *Obj // top-of-stack (implemented with pairs)
-	Env   *Obj // top-level / initial environment
+	Stack   *Obj     // top-of-stack (implemented with pairs)
+	Env     *Scope   // top-level / initial environment
+	Printer *Printer // used by Print and the "print" primitive; callers may reconfigure it
 
-	input string // input data string used by read()
-	pos   uint64 // input data position used by read()
+	scanner        *Scanner // tokenizes the input data string used by Read()
+	retainComments bool     // whether Read keeps comments instead of discarding them
+
+	positions map[*Obj][]Position // Read()'s source position(s) for each *Obj, in read order
+	comments  map[*Obj][]Comment  // Read()'s retained leading comments for each *Obj
 }
 
 func NewNil() *Obj {
@@ -94,14 +100,18 @@ func NewPair(car, cdr *Obj) *Obj {
 	return &Obj{Tag: TagPair, Pair: &Pair{car: car, cdr: cdr}}
 }
 
-func NewClosure(body, env *Obj) *Obj {
+func NewClosure(body *Obj, env *Scope) *Obj {
 	return &Obj{Tag: TagClosure, Closure: &Closure{body: body, env: env}}
 }
 
-func NewPrimitive(f func(env **Obj)) *Obj {
+func NewPrimitive(f func(env **Scope) error) *Obj {
 	return &Obj{Tag: TagPrimitive, Primitive: ptr(Primitive(f))}
 }
 
+// assert and assertTag guard internal invariants that a correct
+// interpreter should never violate; unlike fail/failf/failTag below,
+// they still panic; there's no sensible error value to build when the
+// interpreter's own bookkeeping is broken.
 func assert(v bool, msg string) {
 	if !v {
 		panic(fmt.Sprintf("ASSERT: %s", msg))
@@ -112,18 +122,54 @@ func assertTag(v *Obj, t Tag, msg string) {
 	assert(is(v, t), msg)
 }
 
-func failTag(v *Obj, t Tag, msg string) {
+// fail, failf and failTag build a *ForspError without any position
+// attached. They're used by free functions like car/cdr that have no
+// *Forsp receiver to ask for a current position; callers closer to the
+// read/eval loop use the position-aware (*Forsp).fail/(*Forsp).failf
+// instead.
+func fail(msg string) error {
+	return &ForspError{Msg: msg}
+}
+
+func failf(msg string, args ...any) error {
+	return fail(fmt.Sprintf(msg, args...))
+}
+
+func failTag(v *Obj, t Tag, msg string) error {
 	if !is(v, t) {
-		fail(msg)
+		return fail(msg)
 	}
+
+	return nil
 }
 
-func fail(msg string) {
-	panic(fmt.Sprintf("FAIL: %s", msg))
+// fail and failf attach the reader's current position to the error,
+// for use anywhere a *Forsp is in scope.
+func (f *Forsp) fail(msg string) error {
+	return &ForspError{Pos: f.currentPosition(), Msg: msg}
 }
 
-func failf(msg string, args ...any) {
-	fail(fmt.Sprintf(msg, args...))
+func (f *Forsp) failf(msg string, args ...any) error {
+	return f.fail(fmt.Sprintf(msg, args...))
+}
+
+// failAt is like fail, but prefers obj's recorded Read position over
+// the scanner's current cursor. By the time Compute/eval runs on obj,
+// Read has already consumed the rest of the enclosing form (and
+// likely the forms after it too), so f.currentPosition() no longer
+// points anywhere near obj - failAt is for exactly that case, where
+// the *Obj actually being evaluated is in hand.
+func (f *Forsp) failAt(obj *Obj, msg string) error {
+	pos, ok := f.PositionFor(obj)
+	if !ok {
+		pos = f.currentPosition()
+	}
+
+	return &ForspError{Pos: pos, Msg: msg}
+}
+
+func (f *Forsp) failAtf(obj *Obj, msg string, args ...any) error {
+	return f.failAt(obj, fmt.Sprintf(msg, args...))
 }
 
 func is(v *Obj, t Tag) bool {
@@ -152,14 +198,20 @@ func (f *Forsp) intern(atomBuf string) *Obj {
 	return atom
 }
 
-func car(obj *Obj) *Obj {
-	failTag(obj, TagPair, fmt.Sprintf("Expected Pair to apply car() function got %v", obj))
-	return obj.car
+func car(obj *Obj) (*Obj, error) {
+	if err := failTag(obj, TagPair, fmt.Sprintf("Expected Pair to apply car() function got %v", obj)); err != nil {
+		return nil, err
+	}
+
+	return obj.car, nil
 }
 
-func cdr(obj *Obj) *Obj {
-	failTag(obj, TagPair, fmt.Sprintf("Expected Pair to apply cdr() function got %v", obj))
-	return obj.cdr
+func cdr(obj *Obj) (*Obj, error) {
+	if err := failTag(obj, TagPair, fmt.Sprintf("Expected Pair to apply cdr() function got %v", obj)); err != nil {
+		return nil, err
+	}
+
+	return obj.cdr, nil
 }
 
 func ObjEqual(a *Obj, b *Obj) bool {
@@ -174,17 +226,69 @@ func ObjToInt64(a *Obj) int64 {
 	return 0
 }
 
-func (f *Forsp) peek() byte {
-	if f.pos == uint64(len(f.input)) {
-		return 0
+// currentPosition returns the Position of the byte the reader is
+// currently sitting on.
+func (f *Forsp) currentPosition() Position {
+	return f.scanner.Position()
+}
+
+// recordPosition remembers where Read found obj, so PositionFor can
+// report it later. Atoms/numbers/etc. created outside of Read (e.g. by
+// primitives) simply have no entry.
+//
+// Positions are appended rather than overwritten because atoms are
+// interned: every occurrence of the same symbol in a script shares one
+// *Obj, so a plain map[*Obj]Position would have each later occurrence
+// silently erase the last. Appending keeps all of them, in read order,
+// recoverable through PositionsFor.
+func (f *Forsp) recordPosition(obj *Obj, pos Position) {
+	f.positions[obj] = append(f.positions[obj], pos)
+}
+
+// PositionFor returns the first position Read recorded for obj, if
+// any. For an *Obj read more than once (an interned atom, or the
+// shared empty list ()), this is its first occurrence - use
+// PositionsFor to get them all.
+func (f *Forsp) PositionFor(obj *Obj) (Position, bool) {
+	positions, ok := f.positions[obj]
+	if !ok {
+		return Position{}, false
 	}
 
-	return f.input[f.pos]
+	return positions[0], true
+}
+
+// PositionsFor returns every position Read recorded for obj, in read
+// order. Most callers want PositionFor; this exists for the interned-
+// atom case, where a symbol can be read at more than one location.
+func (f *Forsp) PositionsFor(obj *Obj) ([]Position, bool) {
+	positions, ok := f.positions[obj]
+	return positions, ok
+}
+
+// SetRetainComments controls whether Read discards comments (the
+// default) or keeps them, attached to whichever *Obj they lead, for
+// retrieval through CommentsFor. A formatter that wants to round-trip
+// source with comments intact should set this before reading.
+func (f *Forsp) SetRetainComments(retain bool) {
+	f.retainComments = retain
 }
 
-func (f *Forsp) advance() {
-	assert(f.peek() != 0, "cannot advance further")
-	f.pos++
+// recordComments attaches a run of leading comments to obj, so
+// CommentsFor can report them later. Called by Read only when
+// SetRetainComments(true) is in effect and at least one comment
+// preceded obj.
+func (f *Forsp) recordComments(obj *Obj, comments []Comment) {
+	f.comments[obj] = comments
+}
+
+// CommentsFor returns the comments Read recorded as leading obj the
+// last time it read obj, if SetRetainComments(true) was in effect and
+// any were found. For an *Obj read more than once (an interned atom,
+// or the shared ()), only the latest occurrence's comments are kept.
+func (f *Forsp) CommentsFor(obj *Obj) ([]Comment, bool) {
+	c, ok := f.comments[obj]
+	return c, ok
 }
 
 func isWhite(c uint8) bool { return c == ' ' || c == '\t' || c == '\n' }
@@ -195,197 +299,150 @@ func isPunctuation(c uint8) bool {
 	return c == 0 || isWhite(c) || isDirective(c) || c == '(' || c == ')' || c == ';'
 }
 
-func (f *Forsp) skipWhiteAndComments() {
-	c := f.peek()
-	if c == 0 {
-		return
-	}
+// nextToken scans past any comments, returning the next significant
+// token along with whatever comments led it (only populated when
+// SetRetainComments(true) is in effect).
+func (f *Forsp) nextToken() (tok Token, pos Position, text string, comments []Comment) {
+	for {
+		tok, pos, text = f.scanner.Scan(f.retainComments)
+		if tok != TokenComment {
+			return tok, pos, text, comments
+		}
 
-	// skip whitespace
-	if isWhite(c) {
-		f.advance()
-		f.skipWhiteAndComments()
-		return
+		comments = append(comments, Comment{Pos: pos, Text: text})
 	}
+}
 
-	// skip comment
-	if c == ';' {
-		f.advance()
-		for {
-			c = f.peek()
-			if c == 0 {
-				return
-			}
-			f.advance()
-			if c == '\n' {
-				break
-			}
-		}
-
-		f.skipWhiteAndComments()
-		return
+// scalarObj turns a TokenAtom/TokenNumber's literal text into the
+// *Obj it denotes: an interned atom, or a number.
+func (f *Forsp) scalarObj(tok Token, text string) *Obj {
+	if tok == TokenNumber {
+		n, _ := parseInt64(text)
+		return NewNumber(n)
 	}
+
+	return f.intern(text)
 }
 
-func (f *Forsp) readList() *Obj {
+// readList reads the elements of a list up to its closing ')', which
+// Read has already consumed the opening '(' for.
+func (f *Forsp) readList() (*Obj, error) {
 	if f.readStack == nil {
-		f.skipWhiteAndComments()
-		c := f.peek()
-		if c == ')' {
-			f.advance()
-			return f.nil
+		tok, pos, text, comments := f.nextToken()
+		if tok == TokenRParen {
+			return f.nil, nil
 		}
-	}
 
-	first := f.Read()
-	second := f.readList()
-	return NewPair(first, second)
-}
+		first, err := f.readObj(tok, pos, text, comments)
+		if err != nil {
+			return nil, err
+		}
 
-func (f *Forsp) parseInt64(str string) (int64, bool) {
-	i, err := strconv.ParseInt(str, 10, 64)
-	return i, err == nil
-}
+		second, err := f.readList()
+		if err != nil {
+			return nil, err
+		}
 
-func (f *Forsp) readScalar() *Obj {
-	// otherwise, assume atom or number and read it
-	start := f.pos
-	for !isPunctuation(f.peek()) {
-		f.advance()
+		return NewPair(first, second), nil
 	}
 
-	str := f.input[start:f.pos]
-	// is it a number?
-	if n, ok := f.parseInt64(str); ok {
-		return NewNumber(n)
+	first, err := f.Read()
+	if err != nil {
+		return nil, err
 	}
 
-	// atom
-	return f.intern(str)
-}
-
-func (f *Forsp) Read() *Obj {
-	read_stack := f.readStack
-	if read_stack != nil {
-		f.readStack = cdr(read_stack)
-		return car(read_stack)
+	second, err := f.readList()
+	if err != nil {
+		return nil, err
 	}
 
-	f.skipWhiteAndComments()
+	return NewPair(first, second), nil
+}
 
-	c := f.peek()
-	switch c {
-	case 0:
-		fail("End of input: could not read()")
+// readObj builds the *Obj a single token denotes, recursing into
+// readList for '(' and expanding '^'/'$' into a quote/push-or-pop
+// readStack the same way the original character-at-a-time reader did.
+func (f *Forsp) readObj(tok Token, pos Position, text string, comments []Comment) (*Obj, error) {
+	var obj *Obj
 
-	// A quote?
-	case '\'':
-		f.advance()
-		return f.AtomQuote
+	switch tok {
+	case TokenEOF:
+		return nil, f.fail("End of input: could not read()")
 
-	// A push?
-	case '^':
-		f.advance()
-		var s *Obj
-		s = NewPair(f.AtomPush, s)
-		s = NewPair(f.readScalar(), s)
-		s = NewPair(f.AtomQuote, s)
-		f.readStack = s
+	case TokenQuote:
+		obj = f.AtomQuote
 
-		return f.Read()
+	case TokenPush, TokenPop:
+		opTok, _, opText := f.scanner.ScanOperand()
+		op := f.AtomPush
+		if tok == TokenPop {
+			op = f.AtomPop
+		}
 
-	// A pop?
-	case '$':
-		f.advance()
-		var s *Obj
-		s = NewPair(f.AtomPop, s)
-		s = NewPair(f.readScalar(), s)
+		s := NewPair(op, nil)
+		s = NewPair(f.scalarObj(opTok, opText), s)
 		s = NewPair(f.AtomQuote, s)
 		f.readStack = s
 
 		return f.Read()
 
-	// Read a list?
-	case '(':
-		f.advance()
-		return f.readList()
-
-	}
+	case TokenRParen:
+		return nil, f.fail("Unexpected ')'")
 
-	return f.readScalar()
-}
+	case TokenLParen:
+		list, err := f.readList()
+		if err != nil {
+			return nil, err
+		}
+		obj = list
 
-func (f *Forsp) printListTail(obj *Obj) {
-	if obj == f.nil {
-		fmt.Print(")")
-		return
+	default: // TokenAtom, TokenNumber
+		obj = f.scalarObj(tok, text)
 	}
 
-	if is(obj, TagPair) {
-		fmt.Print(" ")
-		f.PrintRecurse(obj.car)
-		f.printListTail(obj.cdr)
+	f.recordPosition(obj, pos)
+	if len(comments) > 0 {
+		f.recordComments(obj, comments)
 	} else {
-		fmt.Print(" . ")
-		f.PrintRecurse(obj)
-		fmt.Print(")")
-	}
-}
-
-func (f *Forsp) PrintRecurse(obj *Obj) {
-	if obj == f.nil {
-		fmt.Print("()")
-		return
-	}
-
-	switch obj.Tag {
-	case TagNil: // do nothing
-	case TagAtom:
-		fmt.Print(*obj.Atom)
-	case TagNumber:
-		fmt.Print(*obj.Number)
-	case TagPair:
-		fmt.Print("(")
-		f.PrintRecurse(obj.car)
-		f.printListTail(obj.cdr)
-
-	case TagClosure:
-		fmt.Print("CLOSURE<")
-		f.PrintRecurse(obj.body)
-		fmt.Printf(", %p>", obj.env)
-
-	case TagPrimitive:
-		fmt.Printf("PRIM<%p>", obj.Primitive)
+		// obj may be shared (an interned atom, or ()) and carry comments
+		// from an earlier occurrence; drop them so they aren't reported
+		// again for this one.
+		delete(f.comments, obj)
 	}
-}
 
-func (f *Forsp) Print(obj *Obj) {
-	f.PrintRecurse(obj)
-	fmt.Println()
+	return obj, nil
 }
 
-func (f *Forsp) EnvFind(env *Obj, key *Obj) *Obj {
-	if !is(key, TagAtom) {
-		failf("Expected 'key' to be an Atom in env_find() got %v", key)
-	}
+func (f *Forsp) Read() (*Obj, error) {
+	if f.readStack != nil {
+		obj, err := car(f.readStack)
+		if err != nil {
+			return nil, err
+		}
 
-	for v := env; v != f.nil; v = cdr(v) {
-		kv := car(v)
-		if key == car(kv) || *key == *car(kv) {
-			return cdr(kv)
+		rest, err := cdr(f.readStack)
+		if err != nil {
+			return nil, err
 		}
+
+		f.readStack = rest
+		return obj, nil
 	}
 
-	failf("Failed to find key='%s' in environment", *key.Atom)
-	return nil
+	tok, pos, text, comments := f.nextToken()
+	return f.readObj(tok, pos, text, comments)
 }
 
-func (f *Forsp) EnvDefine(env *Obj, key *Obj, val *Obj) *Obj {
-	return NewPair(NewPair(key, val), env)
-}
+// Print writes obj to stdout through f.Printer, followed by a
+// newline - the single rendering path shared by the REPL's "print"
+// primitive and anyone embedding a *Forsp directly.
+func (f *Forsp) Print(obj *Obj) error {
+	if err := f.Printer.Fprint(os.Stdout, obj); err != nil {
+		return err
+	}
 
-func (f *Forsp) EnvDefinePrim(env *Obj, name string, fn func(env **Obj)) *Obj {
-	return f.EnvDefine(env, f.intern(name), NewPrimitive(fn))
+	_, err := fmt.Println()
+	return err
 }
 
 func (f *Forsp) Push(obj *Obj) {
@@ -397,125 +454,322 @@ func (f *Forsp) tryPop() (*Obj, bool) {
 		return nil, false
 	}
 
-	o := car(f.Stack)
-	f.Stack = cdr(f.Stack)
+	o := f.Stack.car
+	f.Stack = f.Stack.cdr
 	return o, true
 }
 
-func (f *Forsp) Pop() *Obj {
+func (f *Forsp) Pop() (*Obj, error) {
 	if ret, ok := f.tryPop(); ok {
-		return ret
+		return ret, nil
 	}
 
-	fail("Value Stack Underflow")
-	return nil
+	return nil, f.fail("Value Stack Underflow")
 }
 
-func (f *Forsp) ComputeEnv(comp *Obj, env *Obj) {
+func (f *Forsp) ComputeEnv(comp *Obj, env *Scope) error {
 	for comp != f.nil {
-		cmd := car(comp)
-		comp = cdr(comp)
+		cmd, err := car(comp)
+		if err != nil {
+			return err
+		}
+
+		comp, err = cdr(comp)
+		if err != nil {
+			return err
+		}
 
 		if cmd == f.AtomQuote {
 			if comp == f.nil {
-				fail("Expected data following a quote form")
+				return f.fail("Expected data following a quote form")
+			}
+
+			data, err := car(comp)
+			if err != nil {
+				return err
+			}
+			f.Push(data)
+
+			comp, err = cdr(comp)
+			if err != nil {
+				return err
 			}
-			f.Push(car(comp))
-			comp = cdr(comp)
 
 			continue
 		}
 
-		f.eval(cmd, &env)
+		if err := f.eval(cmd, &env); err != nil {
+			return err
+		}
 	}
+
+	return nil
 }
 
-func (f *Forsp) eval(expr *Obj, env **Obj) {
+func (f *Forsp) eval(expr *Obj, env **Scope) error {
 	if is(expr, TagAtom) {
-		val := f.EnvFind(*env, expr)
+		val, err := f.EnvFind(*env, expr)
+		if err != nil {
+			return err
+		}
+
 		if is(val, TagClosure) {
-			f.ComputeEnv(val.body, val.env)
+			return f.ComputeEnv(val.body, val.env)
 		} else if is(val, TagPrimitive) {
-			(*val.Primitive)(env)
+			return (*val.Primitive)(env)
 		} else {
 			f.Push(val)
 		}
 	} else if is(expr, TagNil) || is(expr, TagPair) {
-		f.Push(NewClosure(expr, *env))
+		// Sealing *env here - rather than only when it's next mutated -
+		// is what makes the closure see exactly the bindings that exist
+		// right now: any later EnvDefine against *env is forced to fork
+		// a child Scope instead of mutating the map this Closure just
+		// captured.
+		f.Push(NewClosure(expr, (*env).seal()))
 	} else {
 		f.Push(expr)
 	}
+
+	return nil
 }
 
 // Core primitives
-func (f *Forsp) primPush(env **Obj) { f.Push(f.EnvFind(*env, f.Pop())) }
+func (f *Forsp) primPush(env **Scope) error {
+	k, err := f.Pop()
+	if err != nil {
+		return err
+	}
+
+	v, err := f.EnvFind(*env, k)
+	if err != nil {
+		return err
+	}
+
+	f.Push(v)
+	return nil
+}
+
+func (f *Forsp) primPop(env **Scope) error {
+	k, err := f.Pop()
+	if err != nil {
+		return err
+	}
+
+	v, err := f.Pop()
+	if err != nil {
+		return err
+	}
 
-func (f *Forsp) primPop(env **Obj) {
-	k, v := f.Pop(), f.Pop()
 	*env = f.EnvDefine(*env, k, v)
+	return nil
 }
 
-func (f *Forsp) primEq(_ **Obj) {
-	if ObjEqual(f.Pop(), f.Pop()) {
+func (f *Forsp) primEq(_ **Scope) error {
+	a, err := f.Pop()
+	if err != nil {
+		return err
+	}
+
+	b, err := f.Pop()
+	if err != nil {
+		return err
+	}
+
+	if ObjEqual(a, b) {
 		f.Push(f.AtomTrue)
 	} else {
 		f.Push(f.nil)
 	}
+
+	return nil
 }
 
-func (f *Forsp) primCons(_ **Obj) {
-	a, b := f.Pop(), f.Pop()
+func (f *Forsp) primCons(_ **Scope) error {
+	a, err := f.Pop()
+	if err != nil {
+		return err
+	}
+
+	b, err := f.Pop()
+	if err != nil {
+		return err
+	}
+
 	f.Push(NewPair(a, b))
+	return nil
 }
 
-func (f *Forsp) primCar(_ **Obj) { f.Push(car(f.Pop())) }
-func (f *Forsp) primCdr(_ **Obj) { f.Push(cdr(f.Pop())) }
+func (f *Forsp) primCar(_ **Scope) error {
+	v, err := f.Pop()
+	if err != nil {
+		return err
+	}
+
+	c, err := car(v)
+	if err != nil {
+		return err
+	}
+
+	f.Push(c)
+	return nil
+}
+
+func (f *Forsp) primCdr(_ **Scope) error {
+	v, err := f.Pop()
+	if err != nil {
+		return err
+	}
+
+	c, err := cdr(v)
+	if err != nil {
+		return err
+	}
+
+	f.Push(c)
+	return nil
+}
+
+func (f *Forsp) primCswap(_ **Scope) error {
+	v, err := f.Pop()
+	if err != nil {
+		return err
+	}
+
+	if v == f.AtomTrue {
+		a, err := f.Pop()
+		if err != nil {
+			return err
+		}
+
+		b, err := f.Pop()
+		if err != nil {
+			return err
+		}
 
-func (f *Forsp) primCswap(_ **Obj) {
-	if f.Pop() == f.AtomTrue {
-		a, b := f.Pop(), f.Pop()
 		f.Push(a)
 		f.Push(b)
 	}
+
+	return nil
 }
 
-func (f *Forsp) primTag(_ **Obj)   { f.Push(NewNumber(int64(f.Pop().Tag))) }
-func (f *Forsp) primRead(_ **Obj)  { f.Push(f.Read()) }
-func (f *Forsp) primPrint(_ **Obj) { f.Print(f.Pop()) }
+func (f *Forsp) primTag(_ **Scope) error {
+	v, err := f.Pop()
+	if err != nil {
+		return err
+	}
+
+	f.Push(NewNumber(int64(v.Tag)))
+	return nil
+}
+
+func (f *Forsp) primRead(_ **Scope) error {
+	obj, err := f.Read()
+	if err != nil {
+		return err
+	}
+
+	f.Push(obj)
+	return nil
+}
+
+func (f *Forsp) primPrint(_ **Scope) error {
+	v, err := f.Pop()
+	if err != nil {
+		return err
+	}
+
+	return f.Print(v)
+}
 
 // Extra primitives
-func (f *Forsp) primStack(_ **Obj) { f.Push(f.Stack) }
-func (f *Forsp) primEnv(env **Obj) { f.Push(*env) }
+func (f *Forsp) primStack(_ **Scope) error { f.Push(f.Stack); return nil }
+func (f *Forsp) primEnv(env **Scope) error { f.Push(f.materializeScope(*env)); return nil }
+
+func (f *Forsp) primSub(_ **Scope) error {
+	b, err := f.Pop()
+	if err != nil {
+		return err
+	}
+
+	a, err := f.Pop()
+	if err != nil {
+		return err
+	}
 
-func (f *Forsp) primSub(_ **Obj) {
-	b, a := f.Pop(), f.Pop()
 	f.Push(NewNumber(ObjToInt64(a) - ObjToInt64(b)))
+	return nil
 }
 
-func (f *Forsp) primMul(_ **Obj) {
-	b, a := f.Pop(), f.Pop()
+func (f *Forsp) primMul(_ **Scope) error {
+	b, err := f.Pop()
+	if err != nil {
+		return err
+	}
+
+	a, err := f.Pop()
+	if err != nil {
+		return err
+	}
+
 	f.Push(NewNumber(ObjToInt64(a) * ObjToInt64(b)))
+	return nil
 }
 
-func (f *Forsp) primNand(_ **Obj) {
-	b, a := f.Pop(), f.Pop()
+func (f *Forsp) primNand(_ **Scope) error {
+	b, err := f.Pop()
+	if err != nil {
+		return err
+	}
+
+	a, err := f.Pop()
+	if err != nil {
+		return err
+	}
+
 	f.Push(NewNumber(^(ObjToInt64(a) & ObjToInt64(b))))
+	return nil
 }
 
-func (f *Forsp) primLsh(_ **Obj) {
-	b, a := f.Pop(), f.Pop()
+func (f *Forsp) primLsh(_ **Scope) error {
+	b, err := f.Pop()
+	if err != nil {
+		return err
+	}
+
+	a, err := f.Pop()
+	if err != nil {
+		return err
+	}
+
 	f.Push(NewNumber(ObjToInt64(a) << uint(ObjToInt64(b))))
+	return nil
 }
 
-func (f *Forsp) primRsh(_ **Obj) {
-	b, a := f.Pop(), f.Pop()
+func (f *Forsp) primRsh(_ **Scope) error {
+	b, err := f.Pop()
+	if err != nil {
+		return err
+	}
+
+	a, err := f.Pop()
+	if err != nil {
+		return err
+	}
+
 	f.Push(NewNumber(ObjToInt64(a) >> uint(ObjToInt64(b))))
+	return nil
 }
 
 func New() *Forsp {
 	f := Forsp{}
 
 	f.nil = NewNil()
+	f.scanner = NewScanner("", "")
+	f.positions = make(map[*Obj][]Position)
+	f.comments = make(map[*Obj][]Comment)
+	f.Printer = NewPrinter(Config{MaxLineWidth: 80})
 
 	f.internedAtoms = f.nil
 	f.AtomTrue = f.intern("t")
@@ -525,7 +779,7 @@ func New() *Forsp {
 
 	f.Stack = f.nil
 
-	env := f.nil
+	env := NewScope(nil)
 
 	// core primitives
 	env = f.EnvDefinePrim(env, "push", f.primPush)
@@ -550,23 +804,29 @@ func New() *Forsp {
 
 	env = optionalUnsafe(&f, env)
 
-	f.Env = env
+	// Seal the primitive table once it's built: every EnvDefinePrim call
+	// above batched straight into this one Scope's map, but nothing past
+	// this point should be able to mutate it - any later EnvDefine has to
+	// fork a child instead.
+	f.Env = env.seal()
 
 	return &f
 }
 
-func (f *Forsp) SetReader(r io.Reader) error {
+// SetReader resets the reader onto r, reporting positions and errors
+// against filename (e.g. the path passed on the command line, or
+// "<stdin>" for the REPL).
+func (f *Forsp) SetReader(r io.Reader, filename string) error {
 	b, err := io.ReadAll(r)
 	if err != nil {
 		return err
 	}
 
-	f.input = string(b)
-	f.pos = 0
+	f.scanner = NewScanner(filename, string(b))
 
 	return nil
 }
 
-func (f *Forsp) Compute(obj *Obj) {
-	f.ComputeEnv(obj, f.Env)
+func (f *Forsp) Compute(obj *Obj) error {
+	return f.ComputeEnv(obj, f.Env)
 }
@@ -0,0 +1,32 @@
+package forsp
+
+import "fmt"
+
+// Position describes a location in Forsp source text, mirroring the
+// shape of go/token.Position so existing tooling conventions carry
+// over directly: byte Offset plus a 1-based Line/Column pair, scoped
+// to a Filename.
+type Position struct {
+	Filename string
+	Offset   int // byte offset, starting at 0
+	Line     int // line number, starting at 1
+	Column   int // column number (byte count), starting at 1
+}
+
+// IsValid reports whether the position is meaningful, i.e. it was
+// actually recorded while reading rather than left as the zero value.
+func (p Position) IsValid() bool { return p.Line > 0 }
+
+func (p Position) String() string {
+	s := p.Filename
+	if p.IsValid() {
+		if s != "" {
+			s += ":"
+		}
+		s += fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	if s == "" {
+		s = "-"
+	}
+	return s
+}
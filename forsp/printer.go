@@ -0,0 +1,271 @@
+package forsp
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Mode is a set of bit flags controlling how a Printer lays out its
+// output, mirroring the flag-style options go/printer takes.
+type Mode uint
+
+const (
+	// UseTabs indents with a single tab per level instead of Config.Indent spaces.
+	UseTabs Mode = 1 << iota
+	// RawStrings prints atoms as-is, bypassing Config.AtomColorizer even
+	// if one is set - useful for a formatter writing to a file, where
+	// the REPL's colorizer would otherwise leak escape codes into it.
+	RawStrings
+	// OneExprPerLine forces every pair list onto multiple lines, one
+	// element per line, regardless of whether it would fit within
+	// Config.MaxLineWidth.
+	OneExprPerLine
+)
+
+// Config holds the formatting options a Printer renders with.
+type Config struct {
+	Indent       int // spaces per indent level; ignored when Mode has UseTabs
+	Mode         Mode
+	MaxLineWidth int // pair lists wider than this break across multiple lines; <= 0 means never break on width alone
+
+	// AtomColorizer, if set, wraps each atom's literal text (e.g. with
+	// ANSI escapes) before it's written. Suppressed by RawStrings.
+	AtomColorizer func(string) string
+}
+
+// Printer formats *Obj trees to an io.Writer according to a Config,
+// the way go/printer formats an *ast.File according to its own
+// Config: Fprint is the only entry point, so it can back both the
+// REPL's "print" primitive and a standalone formatter such as
+// forspfmt.
+type Printer struct {
+	Config
+}
+
+// NewPrinter returns a Printer that formats according to cfg.
+func NewPrinter(cfg Config) *Printer {
+	return &Printer{Config: cfg}
+}
+
+// Fprint writes obj to w formatted according to p's Config.
+func (p *Printer) Fprint(w io.Writer, obj *Obj) error {
+	s := &printState{w: w, cfg: p.Config, onPath: make(map[*Scope]bool)}
+	s.printObj(obj, 0, false)
+	return s.err
+}
+
+// printState carries the mutable bits of a single Fprint call: the
+// destination, the first write error encountered (subsequent writes
+// become no-ops, matching fmt.Fprint's own short-circuit-on-error
+// behaviour), and the set of Scopes currently being printed on the
+// current path, used to detect a closure whose environment loops back
+// to a closure already being printed.
+type printState struct {
+	w      io.Writer
+	cfg    Config
+	onPath map[*Scope]bool
+	err    error
+}
+
+func (s *printState) write(str string) {
+	if s.err != nil {
+		return
+	}
+
+	_, s.err = io.WriteString(s.w, str)
+}
+
+func (s *printState) indentUnit() string {
+	if s.cfg.Mode&UseTabs != 0 {
+		return "\t"
+	}
+
+	n := s.cfg.Indent
+	if n <= 0 {
+		n = 2
+	}
+
+	return strings.Repeat(" ", n)
+}
+
+func (s *printState) newline(level int) {
+	s.write("\n" + strings.Repeat(s.indentUnit(), level))
+}
+
+func (s *printState) writeAtom(name string) {
+	if s.cfg.AtomColorizer != nil && s.cfg.Mode&RawStrings == 0 {
+		s.write(s.cfg.AtomColorizer(name))
+		return
+	}
+
+	s.write(name)
+}
+
+// oneLine renders obj exactly as printObj would with inline forced,
+// into a string instead of s's writer, so printPair can measure it
+// against MaxLineWidth before committing to a layout. It shares s's
+// onPath map so a cycle found while measuring is also found (and
+// rendered as "#<cycle>" in) the real write.
+func (s *printState) oneLine(obj *Obj, level int) string {
+	var b strings.Builder
+	tmp := &printState{w: &b, cfg: s.cfg, onPath: s.onPath}
+	tmp.printObj(obj, level, true)
+
+	return b.String()
+}
+
+func (s *printState) printObj(obj *Obj, level int, inline bool) {
+	if obj == nil || is(obj, TagNil) {
+		s.write("()")
+		return
+	}
+
+	switch obj.Tag {
+	case TagAtom:
+		s.writeAtom(string(*obj.Atom))
+
+	case TagNumber:
+		s.write(strconv.FormatInt(int64(*obj.Number), 10))
+
+	case TagPair:
+		s.printPair(obj, level, inline)
+
+	case TagClosure:
+		s.write("CLOSURE<")
+		s.printObj(obj.body, level, true)
+		s.write(", env=")
+		s.printScope(obj.env, level)
+		s.write(">")
+
+	case TagPrimitive:
+		s.write(fmt.Sprintf("PRIM<%p>", obj.Primitive))
+	}
+}
+
+// printPair prints a (possibly dotted) list. inline callers (and
+// oneLine measurements, which always pass inline=true) get the naive
+// single-line form directly, with no further measuring - only a
+// non-inline call measures the single-line rendering first,
+// go/printer-style, to decide whether it needs to break onto multiple
+// lines - one element per line, indented one level deeper - because it
+// would exceed MaxLineWidth or OneExprPerLine is set.
+func (s *printState) printPair(obj *Obj, level int, inline bool) {
+	if inline {
+		s.printPairInline(obj, level)
+		return
+	}
+
+	line := s.oneLine(obj, level)
+
+	fits := s.cfg.MaxLineWidth <= 0 || len(strings.Repeat(s.indentUnit(), level))+len(line) <= s.cfg.MaxLineWidth
+	if s.cfg.Mode&OneExprPerLine == 0 && fits {
+		s.write(line)
+		return
+	}
+
+	s.write("(")
+	childLevel := level + 1
+	cur := obj
+	first := true
+
+	for {
+		if !first {
+			s.newline(childLevel)
+		}
+		first = false
+
+		s.printObj(cur.car, childLevel, false)
+
+		switch {
+		case is(cur.cdr, TagNil):
+			s.newline(level)
+			s.write(")")
+			return
+
+		case is(cur.cdr, TagPair):
+			cur = cur.cdr
+
+		default:
+			s.newline(childLevel)
+			s.write(". ")
+			s.printObj(cur.cdr, childLevel, false)
+			s.newline(level)
+			s.write(")")
+			return
+		}
+	}
+}
+
+// printPairInline writes obj as a single-line canonical (possibly
+// dotted) list, recursing into children with inline=true throughout.
+// It never measures or considers breaking onto multiple lines, which
+// is what makes it a valid base case for oneLine's measurement pass.
+func (s *printState) printPairInline(obj *Obj, level int) {
+	s.write("(")
+	s.printObj(obj.car, level, true)
+
+	for cur := obj.cdr; ; {
+		switch {
+		case is(cur, TagNil):
+			s.write(")")
+			return
+
+		case is(cur, TagPair):
+			s.write(" ")
+			s.printObj(cur.car, level, true)
+			cur = cur.cdr
+
+		default:
+			s.write(" . ")
+			s.printObj(cur, level, true)
+			s.write(")")
+			return
+		}
+	}
+}
+
+// printScope prints a closure's captured environment as a chain of
+// binding sets, outermost last. It walks sc.order - most-recently-
+// defined first, duplicates and all on a rebind - rather than ranging
+// over sc.Objects directly, for the same reason materializeScope does:
+// Go map iteration order is unspecified, which would otherwise make
+// printing the same closure twice produce different output each time.
+//
+// A Scope already on the current print path - reachable because a
+// value bound in it is a closure whose own env loops back here, as
+// happens for any closure that recurses - prints as "#<cycle>" instead
+// of recursing forever.
+func (s *printState) printScope(sc *Scope, level int) {
+	if sc == nil {
+		s.write("()")
+		return
+	}
+
+	if s.onPath[sc] {
+		s.write("#<cycle>")
+		return
+	}
+
+	s.onPath[sc] = true
+	defer delete(s.onPath, sc)
+
+	s.write("{")
+	for i := len(sc.order) - 1; i >= 0; i-- {
+		if i != len(sc.order)-1 {
+			s.write(" ")
+		}
+
+		k := sc.order[i]
+		s.printObj(k, level, true)
+		s.write(":")
+		s.printObj(sc.Objects[k], level, true)
+	}
+	s.write("}")
+
+	if sc.Outer != nil {
+		s.write("->")
+		s.printScope(sc.Outer, level)
+	}
+}
@@ -0,0 +1,99 @@
+package forsp
+
+import (
+	"strings"
+	"testing"
+)
+
+func fprintString(t *testing.T, p *Printer, obj *Obj) string {
+	t.Helper()
+	var b strings.Builder
+	if err := p.Fprint(&b, obj); err != nil {
+		t.Fatalf("Fprint: %v", err)
+	}
+	return b.String()
+}
+
+func TestPrinterOneLine(t *testing.T) {
+	f := New()
+	obj := readOne(t, f, "(1 2 3)")
+
+	p := NewPrinter(Config{MaxLineWidth: 80})
+	got := fprintString(t, p, obj)
+
+	if got != "(1 2 3)" {
+		t.Errorf("Fprint = %q, want %q", got, "(1 2 3)")
+	}
+}
+
+func TestPrinterIsStableAcrossRepeatedCalls(t *testing.T) {
+	f := New()
+	obj := readOne(t, f, "(1 (2 3) 4)")
+
+	p := NewPrinter(Config{MaxLineWidth: 80})
+	first := fprintString(t, p, obj)
+
+	for i := 0; i < 5; i++ {
+		if got := fprintString(t, p, obj); got != first {
+			t.Fatalf("Fprint call %d = %q, want %q (same as the first call)", i, got, first)
+		}
+	}
+}
+
+func TestPrinterDottedPair(t *testing.T) {
+	f := New()
+
+	obj := NewPair(NewNumber(1), NewNumber(2))
+
+	p := NewPrinter(Config{MaxLineWidth: 80})
+	got := fprintString(t, p, obj)
+
+	if got != "(1 . 2)" {
+		t.Errorf("Fprint = %q, want %q", got, "(1 . 2)")
+	}
+	_ = f
+}
+
+func TestPrinterBreaksOnMaxLineWidth(t *testing.T) {
+	f := New()
+	obj := readOne(t, f, "(aaaaaaaaaa bbbbbbbbbb cccccccccc dddddddddd)")
+
+	p := NewPrinter(Config{Indent: 2, MaxLineWidth: 20})
+	got := fprintString(t, p, obj)
+
+	want := "(aaaaaaaaaa\n  bbbbbbbbbb\n  cccccccccc\n  dddddddddd\n)"
+	if got != want {
+		t.Errorf("Fprint = %q, want %q", got, want)
+	}
+}
+
+func TestPrinterOneExprPerLineForcesBreaking(t *testing.T) {
+	f := New()
+	obj := readOne(t, f, "(1 2)")
+
+	p := NewPrinter(Config{Indent: 2, Mode: OneExprPerLine, MaxLineWidth: 80})
+	got := fprintString(t, p, obj)
+
+	want := "(1\n  2\n)"
+	if got != want {
+		t.Errorf("Fprint = %q, want %q", got, want)
+	}
+}
+
+func TestPrinterDetectsClosureEnvCycle(t *testing.T) {
+	f := New()
+
+	// Build a Scope whose own binding's value is a Closure capturing
+	// that same Scope, the shape a recursive Forsp closure produces.
+	env := NewScope(nil)
+	self := NewClosure(f.nil, env)
+	env = f.EnvDefine(env, f.intern("self"), self)
+	env.seal()
+
+	p := NewPrinter(Config{MaxLineWidth: 80})
+	got := fprintString(t, p, self)
+
+	if !strings.Contains(got, "#<cycle>") {
+		t.Errorf("Fprint(self-referential closure) = %q, want it to contain %q", got, "#<cycle>")
+	}
+}
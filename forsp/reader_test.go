@@ -0,0 +1,116 @@
+package forsp
+
+import (
+	"strings"
+	"testing"
+)
+
+func mustSetReader(t *testing.T, f *Forsp, src, filename string) {
+	t.Helper()
+	if err := f.SetReader(strings.NewReader(src), filename); err != nil {
+		t.Fatalf("SetReader: %v", err)
+	}
+}
+
+func TestReadPositions(t *testing.T) {
+	f := New()
+	mustSetReader(t, f, "one\ntwo three", "<test>")
+
+	one, err := f.Read()
+	if err != nil {
+		t.Fatalf("Read(one): %v", err)
+	}
+	pos, ok := f.PositionFor(one)
+	if !ok {
+		t.Fatal("PositionFor(one): no position recorded")
+	}
+	if pos.Line != 1 || pos.Column != 1 {
+		t.Errorf("PositionFor(one) = %d:%d, want 1:1", pos.Line, pos.Column)
+	}
+
+	two, err := f.Read()
+	if err != nil {
+		t.Fatalf("Read(two): %v", err)
+	}
+	pos, ok = f.PositionFor(two)
+	if !ok {
+		t.Fatal("PositionFor(two): no position recorded")
+	}
+	if pos.Line != 2 || pos.Column != 1 {
+		t.Errorf("PositionFor(two) = %d:%d, want 2:1", pos.Line, pos.Column)
+	}
+
+	three, err := f.Read()
+	if err != nil {
+		t.Fatalf("Read(three): %v", err)
+	}
+	pos, ok = f.PositionFor(three)
+	if !ok {
+		t.Fatal("PositionFor(three): no position recorded")
+	}
+	if pos.Line != 2 || pos.Column != 5 {
+		t.Errorf("PositionFor(three) = %d:%d, want 2:5", pos.Line, pos.Column)
+	}
+}
+
+func TestReadEOFError(t *testing.T) {
+	f := New()
+	mustSetReader(t, f, "", "<test>")
+
+	_, err := f.Read()
+	if err == nil {
+		t.Fatal("Read on empty input returned no error")
+	}
+
+	fe, ok := err.(*ForspError)
+	if !ok {
+		t.Fatalf("Read error is %T, want *ForspError", err)
+	}
+
+	if !strings.Contains(fe.Error(), "End of input") {
+		t.Errorf("Read error = %q, want it to mention \"End of input\"", fe.Error())
+	}
+}
+
+func TestReadUnexpectedCloseParen(t *testing.T) {
+	f := New()
+	mustSetReader(t, f, ")", "<test>")
+
+	if _, err := f.Read(); err == nil {
+		t.Fatal("Read on a stray ')' returned no error")
+	}
+}
+
+func TestReadRetainsComments(t *testing.T) {
+	f := New()
+	f.SetRetainComments(true)
+	mustSetReader(t, f, "; a comment\nfoo", "<test>")
+
+	obj, err := f.Read()
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+
+	comments, ok := f.CommentsFor(obj)
+	if !ok || len(comments) != 1 {
+		t.Fatalf("CommentsFor(foo) = %v, %v, want one leading comment", comments, ok)
+	}
+
+	if comments[0].Text != "; a comment" {
+		t.Errorf("comment text = %q, want %q", comments[0].Text, "; a comment")
+	}
+}
+
+func TestReadDiscardsCommentsByDefault(t *testing.T) {
+	f := New()
+	mustSetReader(t, f, "; a comment\nfoo", "<test>")
+
+	obj, err := f.Read()
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+
+	if _, ok := f.CommentsFor(obj); ok {
+		t.Error("CommentsFor returned comments despite RetainComments never being set")
+	}
+}
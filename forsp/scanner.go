@@ -0,0 +1,194 @@
+package forsp
+
+import "strconv"
+
+// Token identifies the lexical class of a piece of source text
+// returned by Scanner.Scan, mirroring the go/scanner split from
+// go/parser: the scanner has no opinion on *Obj, interning, or
+// number values, just bytes, positions and token classes (it tells a
+// TokenNumber from a TokenAtom but never converts it), so a syntax
+// highlighter or auto-indenter can consume the token stream without pulling in the
+// reader/evaluator at all.
+type Token int
+
+const (
+	TokenEOF Token = iota
+	TokenLParen
+	TokenRParen
+	TokenQuote
+	TokenPush
+	TokenPop
+	TokenAtom
+	TokenNumber
+	TokenComment
+)
+
+func (t Token) String() string {
+	switch t {
+	case TokenEOF:
+		return "EOF"
+	case TokenLParen:
+		return "("
+	case TokenRParen:
+		return ")"
+	case TokenQuote:
+		return "'"
+	case TokenPush:
+		return "^"
+	case TokenPop:
+		return "$"
+	case TokenAtom:
+		return "Atom"
+	case TokenNumber:
+		return "Number"
+	case TokenComment:
+		return "Comment"
+	}
+
+	return "unknown"
+}
+
+// Comment is a single comment retained by Scan when its retainComments
+// argument is true. Read attaches each run of Comments to the *Obj read
+// right after it, the same way positions are attached - see
+// CommentsFor. Comments followed by a ')', a '^'/'$' directive or the
+// end of input are not attached to anything and are dropped.
+type Comment struct {
+	Pos  Position
+	Text string
+}
+
+// Scanner turns Forsp source text into a stream of tokens. Read is a
+// thin parser built on top of it: Scanner owns the byte-at-a-time
+// advance()/line/column bookkeeping that used to live directly on
+// Forsp, so that tooling (syntax highlighters, auto-indenters) can
+// tokenize Forsp source without dragging in the reader or evaluator.
+type Scanner struct {
+	input    string
+	pos      uint64
+	filename string
+	line     int
+	column   int
+}
+
+// NewScanner returns a Scanner positioned at the start of input,
+// reporting positions against filename.
+func NewScanner(filename, input string) *Scanner {
+	return &Scanner{input: input, filename: filename, line: 1, column: 1}
+}
+
+func (s *Scanner) peek() byte {
+	if s.pos == uint64(len(s.input)) {
+		return 0
+	}
+
+	return s.input[s.pos]
+}
+
+func (s *Scanner) advance() {
+	assert(s.peek() != 0, "cannot advance further")
+
+	if s.input[s.pos] == '\n' {
+		s.line++
+		s.column = 1
+	} else {
+		s.column++
+	}
+
+	s.pos++
+}
+
+// Position returns the Position of the byte the scanner is currently
+// sitting on.
+func (s *Scanner) Position() Position {
+	return Position{Filename: s.filename, Offset: int(s.pos), Line: s.line, Column: s.column}
+}
+
+func parseInt64(str string) (int64, bool) {
+	i, err := strconv.ParseInt(str, 10, 64)
+	return i, err == nil
+}
+
+// scanScalar reads up to the next punctuation byte and classifies the
+// result as a TokenNumber or TokenAtom. It does not skip leading
+// whitespace first: callers that want whitespace skipped go through
+// Scan, and the one that doesn't (ScanOperand, for '^'/'$' directives)
+// relies on that.
+func (s *Scanner) scanScalar() (Token, string) {
+	start := s.pos
+	for !isPunctuation(s.peek()) {
+		s.advance()
+	}
+
+	str := s.input[start:s.pos]
+	if _, ok := parseInt64(str); ok {
+		return TokenNumber, str
+	}
+
+	return TokenAtom, str
+}
+
+// ScanOperand scans the scalar immediately following a '^' or '$'
+// directive, with no intervening whitespace permitted - the directive
+// character and its operand must be written with nothing between
+// them (e.g. "^foo"), matching the reader's original behaviour.
+func (s *Scanner) ScanOperand() (Token, Position, string) {
+	pos := s.Position()
+	tok, text := s.scanScalar()
+	return tok, pos, text
+}
+
+// Scan returns the next token in the input along with its starting
+// Position and literal text. Whitespace is always skipped. Comments
+// are skipped too unless retainComments is set, in which case they're
+// returned as TokenComment instead.
+func (s *Scanner) Scan(retainComments bool) (Token, Position, string) {
+	for {
+		for isWhite(s.peek()) {
+			s.advance()
+		}
+
+		pos := s.Position()
+
+		switch c := s.peek(); c {
+		case 0:
+			return TokenEOF, pos, ""
+
+		case ';':
+			start := s.pos
+			for s.peek() != 0 && s.peek() != '\n' {
+				s.advance()
+			}
+
+			if retainComments {
+				return TokenComment, pos, s.input[start:s.pos]
+			}
+
+			continue
+
+		case '\'':
+			s.advance()
+			return TokenQuote, pos, "'"
+
+		case '^':
+			s.advance()
+			return TokenPush, pos, "^"
+
+		case '$':
+			s.advance()
+			return TokenPop, pos, "$"
+
+		case '(':
+			s.advance()
+			return TokenLParen, pos, "("
+
+		case ')':
+			s.advance()
+			return TokenRParen, pos, ")"
+
+		default:
+			tok, text := s.scanScalar()
+			return tok, pos, text
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package forsp
+
+import "testing"
+
+func TestScannerTokens(t *testing.T) {
+	s := NewScanner("<test>", "(foo 42 ^bar $baz 'quux)")
+
+	want := []struct {
+		tok  Token
+		text string
+	}{
+		{TokenLParen, "("},
+		{TokenAtom, "foo"},
+		{TokenNumber, "42"},
+		{TokenPush, "^"},
+		{TokenAtom, "bar"},
+		{TokenPop, "$"},
+		{TokenAtom, "baz"},
+		{TokenQuote, "'"},
+		{TokenAtom, "quux"},
+		{TokenRParen, ")"},
+		{TokenEOF, ""},
+	}
+
+	for i, w := range want {
+		tok, _, text := s.Scan(false)
+		if tok != w.tok || text != w.text {
+			t.Fatalf("token %d = %v %q, want %v %q", i, tok, text, w.tok, w.text)
+		}
+	}
+}
+
+func TestScannerSkipsCommentsByDefault(t *testing.T) {
+	s := NewScanner("<test>", "; a comment\nfoo")
+
+	tok, _, text := s.Scan(false)
+	if tok != TokenAtom || text != "foo" {
+		t.Fatalf("Scan(false) = %v %q, want TokenAtom \"foo\"", tok, text)
+	}
+}
+
+func TestScannerRetainsComments(t *testing.T) {
+	s := NewScanner("<test>", "; a comment\nfoo")
+
+	tok, _, text := s.Scan(true)
+	if tok != TokenComment || text != "; a comment" {
+		t.Fatalf("Scan(true) = %v %q, want TokenComment \"; a comment\"", tok, text)
+	}
+
+	tok, _, text = s.Scan(true)
+	if tok != TokenAtom || text != "foo" {
+		t.Fatalf("Scan(true) = %v %q, want TokenAtom \"foo\"", tok, text)
+	}
+}
+
+func TestScannerPositions(t *testing.T) {
+	s := NewScanner("<test>", "one\ntwo three")
+
+	_, pos, _ := s.Scan(false)
+	if pos.Line != 1 || pos.Column != 1 {
+		t.Errorf("pos(one) = %d:%d, want 1:1", pos.Line, pos.Column)
+	}
+
+	_, pos, _ = s.Scan(false)
+	if pos.Line != 2 || pos.Column != 1 {
+		t.Errorf("pos(two) = %d:%d, want 2:1", pos.Line, pos.Column)
+	}
+
+	_, pos, _ = s.Scan(false)
+	if pos.Line != 2 || pos.Column != 5 {
+		t.Errorf("pos(three) = %d:%d, want 2:5", pos.Line, pos.Column)
+	}
+}
+
+func TestScanOperandReadsOperandDirectlyAfterDirective(t *testing.T) {
+	s := NewScanner("<test>", "^foo")
+
+	tok, _, _ := s.Scan(false)
+	if tok != TokenPush {
+		t.Fatalf("Scan = %v, want TokenPush", tok)
+	}
+
+	opTok, _, opText := s.ScanOperand()
+	if opTok != TokenAtom || opText != "foo" {
+		t.Fatalf("ScanOperand = %v %q, want TokenAtom \"foo\"", opTok, opText)
+	}
+}
+
+func TestScannerEOFIsIdempotent(t *testing.T) {
+	s := NewScanner("<test>", "")
+
+	for i := 0; i < 2; i++ {
+		tok, _, text := s.Scan(false)
+		if tok != TokenEOF || text != "" {
+			t.Fatalf("Scan on empty input (call %d) = %v %q, want TokenEOF \"\"", i, tok, text)
+		}
+	}
+}
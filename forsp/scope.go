@@ -0,0 +1,115 @@
+package forsp
+
+// Scope is a lexical binding frame: a map from interned atom pointers
+// to their bound value, plus a link to the enclosing Scope. This
+// replaces the original flat (key . val) pair list that EnvFind had
+// to walk one node at a time - ported from the idea behind
+// go/ast/scope.go, adapted for the fact that Forsp builds its
+// environment incrementally at run time rather than once up front.
+//
+// A fresh Scope is mutable: EnvDefine adds straight into its own map.
+// The moment a Scope escapes - captured by a Closure - it is sealed,
+// and any further EnvDefine against it instead conses a fresh child
+// Scope, exactly as the old pair-list EnvDefine did by always
+// allocating a new head. That preserves the property closures relied
+// on for free under the pair-list representation: a closure sees the
+// environment as of its creation, never bindings added afterwards.
+// In return, a run of "pop" bindings with no closure created in
+// between batches into a single map instead of a chain of one-entry
+// links, which is where the speedup comes from - most visibly for the
+// primitive table built once in New(), which collapses into one
+// scope with an O(1) lookup for every primitive instead of an O(n)
+// walk.
+type Scope struct {
+	Outer   *Scope
+	Objects map[*Obj]*Obj
+
+	// order records each EnvDefine against this Scope in call order,
+	// key repeated on every rebind. It exists so materializeScope and
+	// printScope can reproduce the original pair-list env's
+	// deterministic most-recently-defined-first order (duplicates and
+	// all) instead of Go's unspecified map iteration order; EnvFind
+	// never consults it - a map lookup already gives it most-recently-
+	// defined-wins for free.
+	order []*Obj
+
+	sealed bool
+}
+
+// NewScope allocates an empty, mutable Scope chained to outer.
+func NewScope(outer *Scope) *Scope {
+	return &Scope{Outer: outer, Objects: make(map[*Obj]*Obj)}
+}
+
+// seal marks a Scope as escaped: future EnvDefine calls against it
+// must fork rather than mutate, since something else (a Closure) may
+// already be holding onto it. Returns s for chaining at the call site.
+func (s *Scope) seal() *Scope {
+	if s != nil {
+		s.sealed = true
+	}
+
+	return s
+}
+
+func (f *Forsp) EnvFind(env *Scope, key *Obj) (*Obj, error) {
+	if !is(key, TagAtom) {
+		return nil, f.failf("Expected 'key' to be an Atom in env_find() got %v", key)
+	}
+
+	for s := env; s != nil; s = s.Outer {
+		if v, ok := s.Objects[key]; ok {
+			return v, nil
+		}
+	}
+
+	return nil, f.failAtf(key, "Failed to find key='%s' in environment", *key.Atom)
+}
+
+func (f *Forsp) EnvDefine(env *Scope, key *Obj, val *Obj) *Scope {
+	if env != nil && !env.sealed {
+		env.Objects[key] = val
+		env.order = append(env.order, key)
+		return env
+	}
+
+	child := NewScope(env)
+	child.Objects[key] = val
+	child.order = append(child.order, key)
+	return child
+}
+
+func (f *Forsp) EnvDefinePrim(env *Scope, name string, fn func(env **Scope) error) *Scope {
+	return f.EnvDefine(env, f.intern(name), NewPrimitive(fn))
+}
+
+// materializeScope rebuilds the (key . val) pair-list representation
+// a scope chain used to have, for the "env" primitive, which exposes
+// the environment to Forsp code as ordinary data. It prepends each
+// Scope's order slice oldest-first, outermost Scope first, so the
+// result has the same
+// deterministic most-recently-defined-first ordering (including a
+// duplicate pair for each rebind) that scripts scanning "env" by hand
+// relied on under the old pair-list representation, rather than Go's
+// unspecified map iteration order.
+func (f *Forsp) materializeScope(env *Scope) *Obj {
+	var scopes []*Scope
+	for s := env; s != nil; s = s.Outer {
+		scopes = append(scopes, s)
+	}
+
+	// Build outermost-first, each scope's own keys oldest-first, so
+	// that the last pair prepended - and thus the front of the
+	// resulting list - is the innermost scope's most recently defined
+	// key, matching the pair list's original most-recently-defined-
+	// first order.
+	list := f.nil
+	for i := len(scopes) - 1; i >= 0; i-- {
+		s := scopes[i]
+		for _, k := range s.order {
+			list = NewPair(NewPair(k, s.Objects[k]), list)
+		}
+	}
+
+	return list
+}
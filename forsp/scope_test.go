@@ -0,0 +1,149 @@
+package forsp
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+)
+
+func TestEnvDefineAndFind(t *testing.T) {
+	f := New()
+
+	a, b := f.intern("a"), f.intern("b")
+	env := f.EnvDefine(nil, a, NewNumber(1))
+	env = f.EnvDefine(env, b, NewNumber(2))
+
+	got, err := f.EnvFind(env, a)
+	if err != nil {
+		t.Fatalf("EnvFind(a): %v", err)
+	}
+	if *got.Number != 1 {
+		t.Errorf("EnvFind(a) = %v, want 1", *got.Number)
+	}
+
+	got, err = f.EnvFind(env, b)
+	if err != nil {
+		t.Fatalf("EnvFind(b): %v", err)
+	}
+	if *got.Number != 2 {
+		t.Errorf("EnvFind(b) = %v, want 2", *got.Number)
+	}
+}
+
+func TestEnvFindMissingKey(t *testing.T) {
+	f := New()
+
+	if _, err := f.EnvFind(nil, f.intern("missing")); err == nil {
+		t.Fatal("EnvFind on an undefined key returned no error")
+	}
+}
+
+func TestEnvFindOuterScope(t *testing.T) {
+	f := New()
+
+	outer := f.EnvDefine(nil, f.intern("a"), NewNumber(1))
+	inner := NewScope(outer)
+	inner = f.EnvDefine(inner, f.intern("b"), NewNumber(2))
+
+	if _, err := f.EnvFind(inner, f.intern("a")); err != nil {
+		t.Errorf("EnvFind should walk into Outer, got error: %v", err)
+	}
+}
+
+// TestEnvDefineSealing covers the property a Closure relies on: once a
+// Scope has been captured (sealed), a later EnvDefine against it must
+// fork a new child Scope rather than mutate the one the Closure is
+// holding, or a closure would start seeing bindings added after its
+// own creation.
+func TestEnvDefineSealing(t *testing.T) {
+	f := New()
+
+	base := f.EnvDefine(nil, f.intern("a"), NewNumber(1))
+	captured := base // what a Closure would have captured at this point
+	captured.seal()
+
+	after := f.EnvDefine(captured, f.intern("b"), NewNumber(2))
+
+	if after == captured {
+		t.Fatal("EnvDefine mutated a sealed Scope in place instead of forking a child")
+	}
+
+	if _, err := f.EnvFind(captured, f.intern("b")); err == nil {
+		t.Error("the sealed Scope a Closure captured should not see bindings added after sealing")
+	}
+
+	if _, err := f.EnvFind(after, f.intern("b")); err != nil {
+		t.Errorf("the forked child Scope should see its own binding: %v", err)
+	}
+
+	if _, err := f.EnvFind(after, f.intern("a")); err != nil {
+		t.Errorf("the forked child Scope should still see the sealed parent's bindings: %v", err)
+	}
+}
+
+func TestEnvDefineUnsealedMutatesInPlace(t *testing.T) {
+	f := New()
+
+	env := f.EnvDefine(nil, f.intern("a"), NewNumber(1))
+	after := f.EnvDefine(env, f.intern("b"), NewNumber(2))
+
+	if after != env {
+		t.Error("EnvDefine against an unsealed Scope should mutate it in place, not fork")
+	}
+}
+
+// TestMaterializeScopeOrder pins down materializeScope's ordering
+// contract: most-recently-defined key first, walking from the
+// innermost Scope outward, with a rebind producing a duplicate pair
+// rather than overwriting its original position - the same guarantee
+// the original pair-list env gave scripts that scanned it by hand.
+func TestMaterializeScopeOrder(t *testing.T) {
+	f := New()
+
+	outer := f.EnvDefine(nil, f.intern("a"), NewNumber(1))
+	outer = f.EnvDefine(outer, f.intern("b"), NewNumber(2))
+	outer.seal()
+
+	inner := f.EnvDefine(outer, f.intern("c"), NewNumber(3))
+	inner = f.EnvDefine(inner, f.intern("a"), NewNumber(4)) // rebind, shadows the outer "a"
+
+	list := f.materializeScope(inner)
+
+	var gotKeys []string
+	for cur := list; cur != f.nil; cur = cur.cdr {
+		pair := cur.car
+		gotKeys = append(gotKeys, string(*pair.car.Atom))
+	}
+
+	wantKeys := []string{"a", "c", "b", "a"}
+	if fmt.Sprint(gotKeys) != fmt.Sprint(wantKeys) {
+		t.Errorf("materializeScope order = %v, want %v", gotKeys, wantKeys)
+	}
+}
+
+// BenchmarkScopeChainLookup measures EnvFind walking a chain of
+// Scopes: the key it looks up is bound in the outermost of 32 nested
+// one-binding Scopes stacked on top of the primitive table, so every
+// lookup has to walk the whole chain before it finds it.
+func BenchmarkScopeChainLookup(b *testing.B) {
+	f := New()
+
+	const depth = 32
+	env := f.Env
+	var target *Obj
+	for i := 0; i < depth; i++ {
+		key := f.intern("v" + strconv.Itoa(i))
+		env = NewScope(env)
+		env = f.EnvDefine(env, key, NewNumber(int64(i)))
+		if i == 0 {
+			target = key // defined in the outermost of the benchmark's scopes
+		}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := f.EnvFind(env, target); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package forsp
+
+// Visitor's Visit method is invoked for each Obj encountered by Walk.
+// If the result visitor w is not nil, Walk visits each of obj's
+// children with the visitor w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(obj *Obj) (w Visitor)
+}
+
+// Walk traverses an Obj tree in depth-first order: it starts by
+// calling v.Visit(obj); obj must not be nil. If the visitor w returned
+// by v.Visit(obj) is not nil, Walk is invoked recursively with visitor
+// w for each of the children of obj, followed by a call of
+// w.Visit(nil).
+//
+// Only Pair.car/Pair.cdr and Closure.body are descended into;
+// Closure.env is deliberately not walked, since an environment can
+// reach back into the obj being walked and would send Walk into a
+// cycle.
+func Walk(obj *Obj, v Visitor) {
+	if v = v.Visit(obj); v == nil {
+		return
+	}
+
+	switch obj.Tag {
+	case TagPair:
+		Walk(obj.car, v)
+		Walk(obj.cdr, v)
+	case TagClosure:
+		Walk(obj.body, v)
+	}
+
+	v.Visit(nil)
+}
+
+type inspector func(*Obj) bool
+
+func (f inspector) Visit(obj *Obj) Visitor {
+	if f(obj) {
+		return f
+	}
+
+	return nil
+}
+
+// Inspect traverses an Obj tree in depth-first order: it starts by
+// calling f(obj); obj must not be nil. If f returns true, Inspect
+// invokes f recursively for each of the children of obj, followed by
+// a call of f(nil).
+func Inspect(obj *Obj, f func(*Obj) bool) {
+	Walk(obj, inspector(f))
+}
+
+// Transform returns a copy of obj with every node for which f returns
+// a non-nil replacement swapped out, following the same car/cdr/body
+// recursion as Walk (Closure.env is left untouched). f is called on
+// each node, leaves included, before its children; a non-nil result
+// replaces that node's whole subtree and is not descended into, while
+// returning nil keeps the node and recurses into its children.
+//
+// This is the building block for macro expanders and optimization
+// passes: Walk/Inspect only observe a tree, Transform lets a caller
+// rewrite it.
+func Transform(obj *Obj, f func(*Obj) *Obj) *Obj {
+	if obj == nil {
+		return nil
+	}
+
+	if replacement := f(obj); replacement != nil {
+		return replacement
+	}
+
+	switch obj.Tag {
+	case TagPair:
+		return NewPair(Transform(obj.car, f), Transform(obj.cdr, f))
+	case TagClosure:
+		return NewClosure(Transform(obj.body, f), obj.env)
+	default:
+		return obj
+	}
+}
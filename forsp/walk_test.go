@@ -0,0 +1,167 @@
+package forsp
+
+import (
+	"strings"
+	"testing"
+)
+
+func readOne(t *testing.T, f *Forsp, src string) *Obj {
+	t.Helper()
+	if err := f.SetReader(strings.NewReader(src), "<test>"); err != nil {
+		t.Fatalf("SetReader: %v", err)
+	}
+	obj, err := f.Read()
+	if err != nil {
+		t.Fatalf("Read(%q): %v", src, err)
+	}
+	return obj
+}
+
+func TestWalkVisitsPairsDepthFirst(t *testing.T) {
+	f := New()
+	obj := readOne(t, f, "(1 2)")
+
+	var tags []Tag
+	Inspect(obj, func(o *Obj) bool {
+		if o != nil {
+			tags = append(tags, o.Tag)
+		}
+		return true
+	})
+
+	want := []Tag{TagPair, TagNumber, TagPair, TagNumber, TagNil}
+	if len(tags) != len(want) {
+		t.Fatalf("Inspect visited tags = %v, want %v", tags, want)
+	}
+	for i := range want {
+		if tags[i] != want[i] {
+			t.Errorf("Inspect visited tags = %v, want %v", tags, want)
+			break
+		}
+	}
+}
+
+func TestWalkDoesNotDescendIntoClosureEnv(t *testing.T) {
+	f := New()
+
+	env := f.EnvDefine(nil, f.intern("x"), NewNumber(1))
+	clos := NewClosure(f.nil, env)
+
+	var tags []Tag
+	Inspect(clos, func(o *Obj) bool {
+		if o != nil {
+			tags = append(tags, o.Tag)
+		}
+		return true
+	})
+
+	// Only the Closure and its body (Nil) should be visited; if Walk
+	// recursed into Closure.env it would also see the bound Number(1).
+	want := []Tag{TagClosure, TagNil}
+	if len(tags) != len(want) {
+		t.Fatalf("Inspect visited tags = %v, want %v", tags, want)
+	}
+	for i := range want {
+		if tags[i] != want[i] {
+			t.Errorf("Inspect visited tags = %v, want %v", tags, want)
+			break
+		}
+	}
+}
+
+func TestInspectStopsDescendingWhenCallbackReturnsFalse(t *testing.T) {
+	f := New()
+	obj := readOne(t, f, "(1 (2 3))")
+
+	var sawThree bool
+	Inspect(obj, func(o *Obj) bool {
+		if o == nil {
+			return false
+		}
+		if is(o, TagPair) && is(o.car, TagNumber) && *o.car.Number == 2 {
+			return false // don't descend into (2 3)
+		}
+		if is(o, TagNumber) && *o.Number == 3 {
+			sawThree = true
+		}
+		return true
+	})
+
+	if sawThree {
+		t.Error("Inspect descended into a subtree its callback returned false for")
+	}
+}
+
+func TestTransformReplacesMatchingNodes(t *testing.T) {
+	f := New()
+	obj := readOne(t, f, "(1 2 3)")
+
+	out := Transform(obj, func(o *Obj) *Obj {
+		if is(o, TagNumber) && *o.Number == 2 {
+			return NewNumber(99)
+		}
+		return nil
+	})
+
+	got := numberList(f, out)
+	want := []int64{1, 99, 3}
+	if len(got) != len(want) {
+		t.Fatalf("Transform result = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Transform result = %v, want %v", got, want)
+			break
+		}
+	}
+}
+
+func TestTransformLeavesOriginalUnmodified(t *testing.T) {
+	f := New()
+	obj := readOne(t, f, "(1 2 3)")
+
+	Transform(obj, func(o *Obj) *Obj {
+		if is(o, TagNumber) && *o.Number == 2 {
+			return NewNumber(99)
+		}
+		return nil
+	})
+
+	got := numberList(f, obj)
+	want := []int64{1, 2, 3}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("original obj = %v, want untouched %v", got, want)
+			break
+		}
+	}
+}
+
+func TestTransformLeavesClosureEnvUntouched(t *testing.T) {
+	f := New()
+
+	env := f.EnvDefine(nil, f.intern("x"), NewNumber(1))
+	clos := NewClosure(NewNumber(1), env)
+
+	out := Transform(clos, func(o *Obj) *Obj {
+		if is(o, TagNumber) {
+			return NewNumber(int64(*o.Number) + 1)
+		}
+		return nil
+	})
+
+	if out.env != env {
+		t.Error("Transform replaced or copied Closure.env, want it passed through untouched")
+	}
+	if *out.body.Number != 2 {
+		t.Errorf("Transform(closure).body = %v, want 2", *out.body.Number)
+	}
+}
+
+func numberList(f *Forsp, list *Obj) []int64 {
+	var out []int64
+	for cur := list; cur != f.nil; cur = cur.cdr {
+		out = append(out, int64(*cur.car.Number))
+	}
+	return out
+}
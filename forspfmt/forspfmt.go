@@ -0,0 +1,102 @@
+// Command forspfmt is a standalone formatter for Forsp source files,
+// built on forsp.Printer: it reads each top-level form with
+// forsp.Forsp.Read and writes it back out through a configured
+// Printer rather than forsp.Forsp.Print, so its layout is driven by
+// flags instead of fixed to the REPL's default Printer config. It reads
+// with SetRetainComments(true) and re-emits each form's leading
+// comments (via CommentsFor) immediately before it, so round-tripping
+// a file through forspfmt doesn't drop them.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/losinggeneration/forsp-go/forsp"
+)
+
+func loadFile(filename string) (io.Reader, error) {
+	if filename == "" || filename == "-" {
+		return os.Stdin, nil
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	b, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(b), nil
+}
+
+func main() {
+	indent := flag.Int("indent", 2, "spaces per indent level")
+	tabs := flag.Bool("tabs", false, "indent with tabs instead of spaces")
+	maxWidth := flag.Int("max-width", 80, "line width before a list breaks across multiple lines; 0 disables breaking")
+	oneExprPerLine := flag.Bool("one-per-line", false, "always break lists one element per line")
+	flag.Parse()
+
+	filename := flag.Arg(0)
+	r, err := loadFile(filename)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if filename == "" {
+		filename = "<stdin>"
+	}
+
+	f := forsp.New()
+	if err := f.SetReader(r, filename); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	f.SetRetainComments(true)
+
+	mode := forsp.Mode(0)
+	if *tabs {
+		mode |= forsp.UseTabs
+	}
+	if *oneExprPerLine {
+		mode |= forsp.OneExprPerLine
+	}
+
+	printer := forsp.NewPrinter(forsp.Config{
+		Indent:       *indent,
+		Mode:         mode,
+		MaxLineWidth: *maxWidth,
+	})
+
+	for {
+		obj, err := f.Read()
+		if err != nil {
+			if strings.Contains(err.Error(), "End of input") {
+				break
+			}
+
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		comments, _ := f.CommentsFor(obj)
+		for _, c := range comments {
+			fmt.Println(c.Text)
+		}
+
+		if err := printer.Fprint(os.Stdout, obj); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Println()
+	}
+}